@@ -0,0 +1,319 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package internal converts k6 metric samples into Prometheus collectors
+// and serves them on behalf of the output.
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"go.k6.io/k6/metrics"
+)
+
+// PrometheusAdapter converts k6 metric samples into Prometheus collectors,
+// lazily registering one collector per distinct metric into a single
+// prometheus.Registry.
+type PrometheusAdapter struct {
+	Namespace string
+	Subsystem string
+
+	// ExemplarTags lists the k6 sample tag keys (e.g. "trace_id", "vu") that
+	// are attached as exemplars to counters and Trend metrics (classic or
+	// native histogram), when present on a sample.
+	ExemplarTags []string
+
+	// K6 is fed every sample alongside the generic per-metric collectors,
+	// when the k6_vus collector is enabled. Nil if it isn't.
+	K6 *K6RuntimeCollector
+
+	// NativeHistogram, when set, makes Trend metrics (e.g. http_req_duration)
+	// exposed as Prometheus native (sparse, exponential-bucket) histograms
+	// instead of classic fixed-bucket histograms, so they support histogram_quantile() at any
+	// quantile without pre-declared buckets.
+	NativeHistogram NativeHistogramOptions
+
+	registry *prometheus.Registry
+	logger   logrus.FieldLogger
+
+	mutex      sync.Mutex
+	collectors map[string]prometheus.Collector
+}
+
+// HandlerOptions configures the HTTP handler returned by Handler.
+type HandlerOptions struct {
+	// OpenMetrics enables negotiating the OpenMetrics text format when the
+	// scraping client's Accept header asks for it.
+	OpenMetrics bool
+}
+
+// NativeHistogramOptions configures Trend metrics exposed as Prometheus
+// native histograms. See prometheus.HistogramOpts for the semantics of each
+// field.
+type NativeHistogramOptions struct {
+	Enabled          bool
+	BucketFactor     float64
+	MaxBucketNumber  uint32
+	MinResetDuration time.Duration
+}
+
+// NewPrometheusAdapter creates an adapter registering into registry, using
+// namespace/subsystem as the metric name prefix.
+func NewPrometheusAdapter(
+	registry *prometheus.Registry, logger logrus.FieldLogger, namespace, subsystem string,
+) *PrometheusAdapter {
+	return &PrometheusAdapter{
+		Namespace:  namespace,
+		Subsystem:  subsystem,
+		registry:   registry,
+		logger:     logger,
+		collectors: make(map[string]prometheus.Collector),
+	}
+}
+
+// Registry returns the prometheus.Registry the adapter registers into.
+func (a *PrometheusAdapter) Registry() *prometheus.Registry {
+	return a.registry
+}
+
+// Handler returns the HTTP handler serving the registry content, negotiating
+// the OpenMetrics text format when opts.OpenMetrics is set and the client
+// asks for it via the Accept header.
+func (a *PrometheusAdapter) Handler(opts HandlerOptions) http.Handler {
+	return promhttp.HandlerFor(a.registry, promhttp.HandlerOpts{EnableOpenMetrics: opts.OpenMetrics})
+}
+
+// AddMetricSamples feeds buffered k6 samples into their matching collectors.
+func (a *PrometheusAdapter) AddMetricSamples(containers []metrics.SampleContainer) {
+	for _, container := range containers {
+		for _, sample := range container.GetSamples() {
+			a.addSample(sample)
+		}
+	}
+}
+
+func (a *PrometheusAdapter) addSample(sample metrics.Sample) {
+	if a.K6 != nil {
+		a.K6.Observe(sample.Metric.Name, sample.Value)
+	}
+
+	switch sample.Metric.Type {
+	case metrics.Counter:
+		a.addCounter(sample)
+	case metrics.Gauge:
+		a.gauge(sample.Metric).Set(sample.Value)
+	case metrics.Rate:
+		a.rate(sample.Metric).observe(sample.Value != 0)
+	case metrics.Trend:
+		a.addTrend(sample)
+	}
+}
+
+func (a *PrometheusAdapter) addCounter(sample metrics.Sample) {
+	c := a.counter(sample.Metric)
+
+	exemplar := a.exemplar(sample)
+	if len(exemplar) == 0 {
+		c.Add(sample.Value)
+
+		return
+	}
+
+	adder, ok := c.(prometheus.ExemplarAdder)
+	if !ok {
+		c.Add(sample.Value)
+
+		return
+	}
+
+	adder.AddWithExemplar(sample.Value, exemplar)
+}
+
+// addTrend observes sample.Value on the Trend collector, attaching an
+// exemplar when one is configured and the collector supports it (both the
+// classic and the native histogram do, via ObserveWithExemplar).
+func (a *PrometheusAdapter) addTrend(sample metrics.Sample) {
+	o := a.trend(sample.Metric)
+
+	exemplar := a.exemplar(sample)
+	if len(exemplar) == 0 {
+		o.Observe(sample.Value)
+
+		return
+	}
+
+	observer, ok := o.(prometheus.ExemplarObserver)
+	if !ok {
+		o.Observe(sample.Value)
+
+		return
+	}
+
+	observer.ObserveWithExemplar(sample.Value, exemplar)
+}
+
+// exemplar builds the exemplar label set for sample from the configured
+// ExemplarTags, skipping tags the sample doesn't carry.
+func (a *PrometheusAdapter) exemplar(sample metrics.Sample) prometheus.Labels {
+	if len(a.ExemplarTags) == 0 || sample.Tags == nil {
+		return nil
+	}
+
+	var labels prometheus.Labels
+
+	for _, key := range a.ExemplarTags {
+		value, ok := sample.Tags.Get(key)
+		if !ok || value == "" {
+			continue
+		}
+
+		if labels == nil {
+			labels = make(prometheus.Labels, len(a.ExemplarTags))
+		}
+
+		labels[key] = value
+	}
+
+	return labels
+}
+
+func (a *PrometheusAdapter) fqName(m *metrics.Metric) string {
+	return prometheus.BuildFQName(a.Namespace, a.Subsystem, strings.ReplaceAll(m.Name, "-", "_"))
+}
+
+func help(m *metrics.Metric) string {
+	return fmt.Sprintf("k6 %s metric %q", m.Type, m.Name)
+}
+
+func (a *PrometheusAdapter) counter(m *metrics.Metric) prometheus.Counter {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if c, ok := a.collectors[m.Name]; ok {
+		return c.(prometheus.Counter)
+	}
+
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: a.fqName(m), Help: help(m)})
+	a.registry.MustRegister(c)
+	a.collectors[m.Name] = c
+
+	return c
+}
+
+func (a *PrometheusAdapter) gauge(m *metrics.Metric) prometheus.Gauge {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if c, ok := a.collectors[m.Name]; ok {
+		return c.(prometheus.Gauge)
+	}
+
+	c := prometheus.NewGauge(prometheus.GaugeOpts{Name: a.fqName(m), Help: help(m)})
+	a.registry.MustRegister(c)
+	a.collectors[m.Name] = c
+
+	return c
+}
+
+// trend returns the collector backing a k6 Trend metric: a classic
+// (fixed-bucket) Histogram, or a Prometheus native histogram when
+// a.NativeHistogram is enabled. Both support ObserveWithExemplar, unlike
+// prometheus.Summary, so ExemplarTags attach to Trend metrics either way.
+func (a *PrometheusAdapter) trend(m *metrics.Metric) prometheus.Observer {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if c, ok := a.collectors[m.Name]; ok {
+		return c.(prometheus.Observer)
+	}
+
+	var c interface {
+		prometheus.Collector
+		prometheus.Observer
+	}
+
+	if a.NativeHistogram.Enabled {
+		c = prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:                            a.fqName(m),
+			Help:                            help(m),
+			NativeHistogramBucketFactor:     a.NativeHistogram.BucketFactor,
+			NativeHistogramMaxBucketNumber:  a.NativeHistogram.MaxBucketNumber,
+			NativeHistogramMinResetDuration: a.NativeHistogram.MinResetDuration,
+		})
+	} else {
+		c = prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    a.fqName(m),
+			Help:    help(m),
+			Buckets: prometheus.DefBuckets,
+		})
+	}
+
+	a.registry.MustRegister(c)
+	a.collectors[m.Name] = c
+
+	return c
+}
+
+// rateCollector tracks the ratio of non-zero observations as a Gauge, since
+// k6 Rate metrics carry no running total of their own.
+type rateCollector struct {
+	prometheus.Gauge
+
+	mutex sync.Mutex
+	hits  float64
+	total float64
+}
+
+func (r *rateCollector) observe(hit bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.total++
+	if hit {
+		r.hits++
+	}
+
+	r.Gauge.Set(r.hits / r.total)
+}
+
+func (a *PrometheusAdapter) rate(m *metrics.Metric) *rateCollector {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if c, ok := a.collectors[m.Name]; ok {
+		return c.(*rateCollector)
+	}
+
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: a.fqName(m), Help: help(m)})
+	a.registry.MustRegister(g)
+	c := &rateCollector{Gauge: g}
+	a.collectors[m.Name] = c
+
+	return c
+}
@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.k6.io/k6/metrics"
+)
+
+func TestTrendDefaultSupportsExemplars(t *testing.T) {
+	a := NewPrometheusAdapter(prometheus.NewRegistry(), nil, "", "")
+
+	o := a.trend(&metrics.Metric{Name: "http_req_duration", Type: metrics.Trend})
+
+	if _, ok := o.(prometheus.ExemplarObserver); !ok {
+		t.Error("trend() collector doesn't implement ExemplarObserver; the Exemplars option would silently no-op")
+	}
+}
+
+func TestTrendNativeHistogramSupportsExemplars(t *testing.T) {
+	a := NewPrometheusAdapter(prometheus.NewRegistry(), nil, "", "")
+	a.NativeHistogram = NativeHistogramOptions{Enabled: true, BucketFactor: 1.1}
+
+	o := a.trend(&metrics.Metric{Name: "http_req_duration", Type: metrics.Trend})
+
+	if _, ok := o.(prometheus.ExemplarObserver); !ok {
+		t.Error("native histogram trend collector doesn't implement ExemplarObserver")
+	}
+}
@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Names accepted by the ?collectors= query option.
+const (
+	CollectorGo           = "go"
+	CollectorProcess      = "process"
+	CollectorK6VUs        = "k6_vus"
+	CollectorPushHTTPPool = "push_http_pool"
+)
+
+// RegisterCollectors registers the requested built-in collectors (Go
+// runtime, process, k6 runtime stats, push client pool stats) into registry,
+// alongside the sample adapter. Duplicate and unknown names are ignored, so
+// a repeated or mistyped ?collectors= value can't crash the run with a
+// prometheus.AlreadyRegisteredError.
+func RegisterCollectors(
+	registry *prometheus.Registry, names []string, k6 *K6RuntimeCollector, pushPool *PushClientPoolCollector,
+) {
+	seen := make(map[string]bool, len(names))
+
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+
+		seen[name] = true
+
+		switch name {
+		case CollectorGo:
+			registry.MustRegister(collectors.NewGoCollector())
+		case CollectorProcess:
+			registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		case CollectorK6VUs:
+			registry.MustRegister(k6)
+		case CollectorPushHTTPPool:
+			registry.MustRegister(pushPool)
+		}
+	}
+}
+
+// K6RuntimeCollector exposes k6 execution-engine diagnostics (VU counts,
+// iteration counts, dropped iterations) as their own collector, independent
+// of the generic per-sample metrics the adapter already exposes.
+type K6RuntimeCollector struct {
+	vus               prometheus.Gauge
+	vusMax            prometheus.Gauge
+	iterations        prometheus.Counter
+	droppedIterations prometheus.Counter
+}
+
+// NewK6RuntimeCollector creates a K6RuntimeCollector whose metrics are
+// prefixed with namespace/subsystem, same as the rest of the adapter.
+func NewK6RuntimeCollector(namespace, subsystem string) *K6RuntimeCollector {
+	fqName := func(name string) string {
+		return prometheus.BuildFQName(namespace, subsystem, name)
+	}
+
+	return &K6RuntimeCollector{
+		vus: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: fqName("k6_vus"), Help: "Current number of active virtual users",
+		}),
+		vusMax: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: fqName("k6_vus_max"), Help: "Maximum number of virtual users k6 can scale up to",
+		}),
+		iterations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: fqName("k6_iterations_total"), Help: "Total number of completed iterations",
+		}),
+		droppedIterations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: fqName("k6_dropped_iterations_total"), Help: "Total number of iterations dropped by the scheduler",
+		}),
+	}
+}
+
+func (c *K6RuntimeCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.vus.Describe(ch)
+	c.vusMax.Describe(ch)
+	c.iterations.Describe(ch)
+	c.droppedIterations.Describe(ch)
+}
+
+func (c *K6RuntimeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.vus.Collect(ch)
+	c.vusMax.Collect(ch)
+	c.iterations.Collect(ch)
+	c.droppedIterations.Collect(ch)
+}
+
+// Observe updates the collector from a k6 builtin metric sample, reporting
+// whether metricName was one it tracks.
+func (c *K6RuntimeCollector) Observe(metricName string, value float64) bool {
+	switch metricName {
+	case "vus":
+		c.vus.Set(value)
+	case "vus_max":
+		c.vusMax.Set(value)
+	case "iterations":
+		c.iterations.Add(value)
+	case "dropped_iterations":
+		c.droppedIterations.Add(value)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// PushClientPoolCollector exposes in-flight/total request counts for the
+// extension's own outbound remote_write/Pushgateway push client. This is
+// NOT a view into k6's HTTP execution pool (the VUs' http.* calls) — it
+// only ever moves while this extension is actively pushing, i.e. in
+// mode=remote_write or with pushgateway set. In the default scrape mode
+// there's no outbound push client to instrument, so these metrics stay at
+// zero if enabled there.
+type PushClientPoolCollector struct {
+	inFlight prometheus.Gauge
+	total    prometheus.Counter
+}
+
+// NewPushClientPoolCollector creates a PushClientPoolCollector whose metrics
+// are prefixed with namespace/subsystem, same as the rest of the adapter.
+func NewPushClientPoolCollector(namespace, subsystem string) *PushClientPoolCollector {
+	fqName := func(name string) string {
+		return prometheus.BuildFQName(namespace, subsystem, name)
+	}
+
+	return &PushClientPoolCollector{
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: fqName("push_http_pool_in_flight_requests"), Help: "Current number of in-flight push requests",
+		}),
+		total: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: fqName("push_http_pool_requests_total"), Help: "Total number of push requests sent",
+		}),
+	}
+}
+
+func (c *PushClientPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.inFlight.Describe(ch)
+	c.total.Describe(ch)
+}
+
+func (c *PushClientPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.inFlight.Collect(ch)
+	c.total.Collect(ch)
+}
+
+// Wrap instruments next with in-flight/total request counters, returning a
+// RoundTripper that otherwise behaves exactly like next.
+func (c *PushClientPoolCollector) Wrap(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		c.inFlight.Inc()
+		defer c.inFlight.Dec()
+
+		c.total.Inc()
+
+		return next.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRegisterCollectorsDedupesNames(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	k6 := NewK6RuntimeCollector("", "")
+
+	RegisterCollectors(registry, []string{CollectorK6VUs, CollectorK6VUs, CollectorGo, CollectorGo}, k6, nil)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+
+	if len(families) == 0 {
+		t.Fatal("Gather returned no metric families")
+	}
+}
+
+func TestRegisterCollectorsUnknownNameIgnored(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	RegisterCollectors(registry, []string{"not_a_real_collector"}, nil, nil)
+
+	if families, err := registry.Gather(); err != nil || len(families) != 0 {
+		t.Errorf("Gather = %v, %v; want no families for an unknown collector name", families, err)
+	}
+}
+
+func TestPushClientPoolCollectorWrap(t *testing.T) {
+	pool := NewPushClientPoolCollector("", "")
+
+	rt := pool.Wrap(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.test", nil) //nolint:noctx
+
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(pool)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+
+	var total float64
+
+	for _, f := range families {
+		if f.GetName() == "push_http_pool_requests_total" {
+			total = f.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+
+	if total != 1 {
+		t.Errorf("push_http_pool_requests_total = %v, want 1", total)
+	}
+}
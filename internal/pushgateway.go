@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// PushgatewayConfig holds the settings needed to push a registry to a
+// classic Prometheus Pushgateway.
+type PushgatewayConfig struct {
+	URL      string
+	Job      string
+	Grouping map[string]string
+
+	// HTTPPool, when set, instruments the push client with in-flight/total
+	// request counters.
+	HTTPPool *PushClientPoolCollector
+}
+
+// PushgatewayClient pushes (and, on demand, deletes) a prometheus.Registry's
+// content to a Pushgateway, grouped under Job/Grouping.
+type PushgatewayClient struct {
+	pusher *push.Pusher
+}
+
+// NewPushgatewayClient creates a client targeting cfg.URL, gathering from
+// registry.
+func NewPushgatewayClient(cfg PushgatewayConfig, registry *prometheus.Registry) *PushgatewayClient {
+	pusher := push.New(cfg.URL, cfg.Job).Gatherer(registry)
+
+	for name, value := range cfg.Grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+
+	if cfg.HTTPPool != nil {
+		pusher = pusher.Client(&http.Client{Transport: cfg.HTTPPool.Wrap(http.DefaultTransport)})
+	}
+
+	return &PushgatewayClient{pusher: pusher}
+}
+
+// Push PUTs the current registry content to the gateway, replacing the
+// previously pushed group.
+func (c *PushgatewayClient) Push() error {
+	return c.pusher.Push()
+}
+
+// Delete removes the pushed group from the gateway.
+func (c *PushgatewayClient) Delete() error {
+	return c.pusher.Delete()
+}
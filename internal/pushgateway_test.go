@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPushgatewayClientPush(t *testing.T) {
+	var gotMethod, gotPath string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := NewPushgatewayClient(PushgatewayConfig{
+		URL:      srv.URL,
+		Job:      "my-job",
+		Grouping: map[string]string{"instance": "a"},
+	}, prometheus.NewRegistry())
+
+	if err := c.Push(); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	if gotMethod != http.MethodPut {
+		t.Errorf("method = %q, want PUT", gotMethod)
+	}
+
+	if gotPath != "/metrics/job/my-job/instance/a" {
+		t.Errorf("path = %q, want /metrics/job/my-job/instance/a", gotPath)
+	}
+}
+
+func TestPushgatewayClientDelete(t *testing.T) {
+	var gotMethod string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	c := NewPushgatewayClient(PushgatewayConfig{URL: srv.URL, Job: "my-job"}, prometheus.NewRegistry())
+
+	if err := c.Delete(); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if gotMethod != http.MethodDelete {
+		t.Errorf("method = %q, want DELETE", gotMethod)
+	}
+}
+
+func TestPushgatewayClientHTTPPool(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	pool := NewPushClientPoolCollector("", "")
+
+	c := NewPushgatewayClient(PushgatewayConfig{URL: srv.URL, Job: "my-job", HTTPPool: pool}, prometheus.NewRegistry())
+
+	if err := c.Push(); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(pool)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+
+	var total float64
+
+	for _, f := range families {
+		if f.GetName() == "push_http_pool_requests_total" {
+			total = f.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+
+	if total != 1 {
+		t.Errorf("push_http_pool_requests_total = %v, want 1", total)
+	}
+}
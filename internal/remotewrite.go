@@ -0,0 +1,278 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/sirupsen/logrus"
+)
+
+// RemoteWriteConfig holds the settings needed to push samples to a
+// Prometheus remote_write compatible endpoint (Cortex, Mimir, Thanos,
+// VictoriaMetrics, ...).
+type RemoteWriteConfig struct {
+	URL                string
+	PushInterval       time.Duration
+	BasicAuthUser      string
+	BasicAuthPass      string
+	BearerToken        string
+	Headers            map[string]string
+	InsecureSkipVerify bool
+
+	// HTTPPool, when set, instruments the push client with in-flight/total
+	// request counters.
+	HTTPPool *PushClientPoolCollector
+}
+
+const remoteWriteMaxAttempts = 5
+
+// RemoteWritePusher periodically gathers a prometheus.Registry and ships it
+// to a remote_write endpoint as a snappy-compressed prompb.WriteRequest.
+type RemoteWritePusher struct {
+	cfg      RemoteWriteConfig
+	registry *prometheus.Registry
+	logger   logrus.FieldLogger
+	client   *http.Client
+}
+
+// NewRemoteWritePusher creates a pusher gathering registry and sending to
+// cfg.URL on every Push call.
+func NewRemoteWritePusher(
+	cfg RemoteWriteConfig, registry *prometheus.Registry, logger logrus.FieldLogger,
+) *RemoteWritePusher {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify} //nolint:gosec
+
+	var roundTripper http.RoundTripper = transport
+	if cfg.HTTPPool != nil {
+		roundTripper = cfg.HTTPPool.Wrap(transport)
+	}
+
+	return &RemoteWritePusher{
+		cfg:      cfg,
+		registry: registry,
+		logger:   logger,
+		client:   &http.Client{Transport: roundTripper},
+	}
+}
+
+// Push gathers the registry and sends it, retrying with exponential backoff
+// on 5xx responses.
+func (p *RemoteWritePusher) Push(ctx context.Context) error {
+	families, err := p.registry.Gather()
+	if err != nil {
+		return err
+	}
+
+	req := &prompb.WriteRequest{Timeseries: toTimeSeries(families)}
+
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	return p.send(ctx, snappy.Encode(nil, data))
+}
+
+func (p *RemoteWritePusher) send(ctx context.Context, body []byte) error {
+	backoff := time.Second
+
+	var lastErr error
+
+	for attempt := 1; attempt <= remoteWriteMaxAttempts; attempt++ {
+		err := p.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		var notRetryable *nonRetryableError
+		if errors.As(err, &notRetryable) {
+			return err
+		}
+
+		lastErr = err
+
+		p.logger.WithError(err).WithField("attempt", attempt).Warn("remote_write push failed")
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return fmt.Errorf("remote_write: giving up after %d attempts: %w", remoteWriteMaxAttempts, lastErr)
+}
+
+func (p *RemoteWritePusher) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	for k, v := range p.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	switch {
+	case p.cfg.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+p.cfg.BearerToken)
+	case p.cfg.BasicAuthUser != "":
+		req.SetBasicAuth(p.cfg.BasicAuthUser, p.cfg.BasicAuthPass)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("remote_write: server error: %s", resp.Status)
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return &nonRetryableError{fmt.Errorf("remote_write: unexpected status: %s", resp.Status)}
+	}
+
+	return nil
+}
+
+// nonRetryableError wraps an error that should abort the retry loop
+// immediately instead of being retried with backoff.
+type nonRetryableError struct{ err error }
+
+func (e *nonRetryableError) Error() string { return e.err.Error() }
+func (e *nonRetryableError) Unwrap() error { return e.err }
+
+func toTimeSeries(families []*dto.MetricFamily) []prompb.TimeSeries {
+	var series []prompb.TimeSeries
+
+	now := time.Now().UnixMilli()
+
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			labels := []prompb.Label{{Name: "__name__", Value: family.GetName()}}
+			for _, lp := range m.GetLabel() {
+				labels = append(labels, prompb.Label{Name: lp.GetName(), Value: lp.GetValue()})
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				series = append(series, sampleSeries(labels, m.GetCounter().GetValue(), now))
+			case dto.MetricType_GAUGE:
+				series = append(series, sampleSeries(labels, m.GetGauge().GetValue(), now))
+			case dto.MetricType_SUMMARY:
+				series = append(series, summarySeries(labels, m.GetSummary(), now)...)
+			case dto.MetricType_HISTOGRAM:
+				series = append(series, histogramSeries(labels, m.GetHistogram(), now)...)
+			case dto.MetricType_UNTYPED:
+				series = append(series, sampleSeries(labels, m.GetUntyped().GetValue(), now))
+			}
+		}
+	}
+
+	return series
+}
+
+func sampleSeries(labels []prompb.Label, value float64, timestampMs int64) prompb.TimeSeries {
+	return prompb.TimeSeries{
+		Labels:  labels,
+		Samples: []prompb.Sample{{Value: value, Timestamp: timestampMs}},
+	}
+}
+
+func withSuffix(labels []prompb.Label, suffix string, extra ...prompb.Label) []prompb.Label {
+	out := make([]prompb.Label, len(labels), len(labels)+len(extra))
+	copy(out, labels)
+	out[0] = prompb.Label{Name: "__name__", Value: out[0].Value + suffix}
+
+	return append(out, extra...)
+}
+
+func summarySeries(labels []prompb.Label, s *dto.Summary, timestampMs int64) []prompb.TimeSeries {
+	series := []prompb.TimeSeries{
+		sampleSeries(withSuffix(labels, "_sum"), s.GetSampleSum(), timestampMs),
+		sampleSeries(withSuffix(labels, "_count"), float64(s.GetSampleCount()), timestampMs),
+	}
+
+	for _, q := range s.GetQuantile() {
+		quantile := prompb.Label{Name: "quantile", Value: fmt.Sprintf("%g", q.GetQuantile())}
+		series = append(series, sampleSeries(withSuffix(labels, "", quantile), q.GetValue(), timestampMs))
+	}
+
+	return series
+}
+
+func histogramSeries(labels []prompb.Label, h *dto.Histogram, timestampMs int64) []prompb.TimeSeries {
+	if isNativeHistogram(h) {
+		return []prompb.TimeSeries{nativeHistogramSeries(labels, h, timestampMs)}
+	}
+
+	series := []prompb.TimeSeries{
+		sampleSeries(withSuffix(labels, "_sum"), h.GetSampleSum(), timestampMs),
+		sampleSeries(withSuffix(labels, "_count"), float64(h.GetSampleCount()), timestampMs),
+	}
+
+	for _, b := range h.GetBucket() {
+		le := prompb.Label{Name: "le", Value: fmt.Sprintf("%g", b.GetUpperBound())}
+		series = append(series, sampleSeries(withSuffix(labels, "_bucket", le), float64(b.GetCumulativeCount()), timestampMs))
+	}
+
+	return series
+}
+
+// isNativeHistogram reports whether h is a Prometheus native (sparse,
+// exponential-bucket) histogram rather than a classic fixed-bucket one: a
+// native histogram carries a Schema but no classic Bucket entries, with the
+// actual distribution living in the Zero/Positive/Negative fields instead.
+func isNativeHistogram(h *dto.Histogram) bool {
+	return h.Schema != nil && len(h.GetBucket()) == 0
+}
+
+// nativeHistogramSeries converts a native histogram into its prompb wire
+// representation, carrying the sparse exponential buckets directly instead
+// of flattening them into classic le-bucketed series (which would silently
+// drop the distribution, since native histograms have no Bucket entries).
+func nativeHistogramSeries(labels []prompb.Label, h *dto.Histogram, timestampMs int64) prompb.TimeSeries {
+	return prompb.TimeSeries{
+		Labels: labels,
+		Histograms: []prompb.Histogram{{
+			Count:          &prompb.Histogram_CountInt{CountInt: h.GetSampleCount()},
+			Sum:            h.GetSampleSum(),
+			Schema:         h.GetSchema(),
+			ZeroThreshold:  h.GetZeroThreshold(),
+			ZeroCount:      &prompb.Histogram_ZeroCountInt{ZeroCountInt: h.GetZeroCount()},
+			NegativeSpans:  toBucketSpans(h.GetNegativeSpan()),
+			NegativeDeltas: h.GetNegativeDelta(),
+			PositiveSpans:  toBucketSpans(h.GetPositiveSpan()),
+			PositiveDeltas: h.GetPositiveDelta(),
+			Timestamp:      timestampMs,
+		}},
+	}
+}
+
+func toBucketSpans(spans []*dto.BucketSpan) []prompb.BucketSpan {
+	out := make([]prompb.BucketSpan, len(spans))
+
+	for i, s := range spans {
+		out[i] = prompb.BucketSpan{Offset: s.GetOffset(), Length: s.GetLength()}
+	}
+
+	return out
+}
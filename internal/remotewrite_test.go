@@ -0,0 +1,226 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/sirupsen/logrus"
+)
+
+func strPtr(s string) *string                     { return &s }
+func f64Ptr(f float64) *float64                   { return &f }
+func u64Ptr(u uint64) *uint64                     { return &u }
+func i32Ptr(i int32) *int32                       { return &i }
+func u32Ptr(u uint32) *uint32                     { return &u }
+func metricType(t dto.MetricType) *dto.MetricType { return &t }
+
+func TestToTimeSeriesCounterAndGauge(t *testing.T) {
+	families := []*dto.MetricFamily{
+		{
+			Name: strPtr("reqs_total"),
+			Type: metricType(dto.MetricType_COUNTER),
+			Metric: []*dto.Metric{
+				{Counter: &dto.Counter{Value: f64Ptr(3)}},
+			},
+		},
+		{
+			Name: strPtr("vus"),
+			Type: metricType(dto.MetricType_GAUGE),
+			Metric: []*dto.Metric{
+				{Gauge: &dto.Gauge{Value: f64Ptr(5)}},
+			},
+		},
+	}
+
+	series := toTimeSeries(families)
+
+	if len(series) != 2 {
+		t.Fatalf("len(series) = %d, want 2", len(series))
+	}
+
+	if series[0].Labels[0].Value != "reqs_total" || series[0].Samples[0].Value != 3 {
+		t.Errorf("counter series = %+v", series[0])
+	}
+
+	if series[1].Labels[0].Value != "vus" || series[1].Samples[0].Value != 5 {
+		t.Errorf("gauge series = %+v", series[1])
+	}
+}
+
+func TestSummarySeries(t *testing.T) {
+	labels := []prompb.Label{{Name: "__name__", Value: "http_req_duration"}}
+	s := &dto.Summary{
+		SampleSum:   f64Ptr(12.5),
+		SampleCount: u64Ptr(4),
+		Quantile: []*dto.Quantile{
+			{Quantile: f64Ptr(0.5), Value: f64Ptr(1.2)},
+			{Quantile: f64Ptr(0.99), Value: f64Ptr(3.4)},
+		},
+	}
+
+	series := summarySeries(labels, s, 1000)
+
+	if len(series) != 4 {
+		t.Fatalf("len(series) = %d, want 4 (_sum, _count, 2 quantiles)", len(series))
+	}
+
+	if series[0].Labels[0].Value != "http_req_duration_sum" || series[0].Samples[0].Value != 12.5 {
+		t.Errorf("_sum series = %+v", series[0])
+	}
+
+	if series[1].Labels[0].Value != "http_req_duration_count" || series[1].Samples[0].Value != 4 {
+		t.Errorf("_count series = %+v", series[1])
+	}
+}
+
+func TestHistogramSeriesClassic(t *testing.T) {
+	labels := []prompb.Label{{Name: "__name__", Value: "http_req_duration"}}
+	h := &dto.Histogram{
+		SampleSum:   f64Ptr(8),
+		SampleCount: u64Ptr(2),
+		Bucket: []*dto.Bucket{
+			{UpperBound: f64Ptr(0.5), CumulativeCount: u64Ptr(1)},
+			{UpperBound: f64Ptr(1), CumulativeCount: u64Ptr(2)},
+		},
+	}
+
+	series := histogramSeries(labels, h, 1000)
+
+	if len(series) != 4 {
+		t.Fatalf("len(series) = %d, want 4 (_sum, _count, 2 buckets)", len(series))
+	}
+
+	if series[2].Labels[len(series[2].Labels)-1].Name != "le" {
+		t.Errorf("bucket series missing le label: %+v", series[2])
+	}
+
+	for _, ts := range series {
+		if len(ts.Histograms) != 0 {
+			t.Errorf("classic histogram series must not carry native Histograms: %+v", ts)
+		}
+	}
+}
+
+func TestHistogramSeriesNative(t *testing.T) {
+	labels := []prompb.Label{{Name: "__name__", Value: "http_req_duration"}}
+	h := &dto.Histogram{
+		SampleSum:     f64Ptr(8),
+		SampleCount:   u64Ptr(2),
+		Schema:        i32Ptr(3),
+		ZeroThreshold: f64Ptr(0.001),
+		ZeroCount:     u64Ptr(0),
+		PositiveSpan:  []*dto.BucketSpan{{Offset: i32Ptr(0), Length: u32Ptr(2)}},
+		PositiveDelta: []int64{1, 1},
+	}
+
+	series := histogramSeries(labels, h, 1000)
+
+	if len(series) != 1 {
+		t.Fatalf("len(series) = %d, want 1 (single native histogram series)", len(series))
+	}
+
+	ts := series[0]
+	if len(ts.Histograms) != 1 {
+		t.Fatalf("Histograms = %d entries, want 1", len(ts.Histograms))
+	}
+
+	native := ts.Histograms[0]
+	if native.Schema != 3 {
+		t.Errorf("Schema = %d, want 3", native.Schema)
+	}
+
+	if native.Sum != 8 {
+		t.Errorf("Sum = %v, want 8", native.Sum)
+	}
+
+	if len(native.PositiveSpans) != 1 || len(native.PositiveDeltas) != 2 {
+		t.Errorf("PositiveSpans/Deltas = %+v/%+v", native.PositiveSpans, native.PositiveDeltas)
+	}
+}
+
+func TestRemoteWritePusherPostSuccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" {
+			t.Errorf("Content-Encoding = %q, want snappy", r.Header.Get("Content-Encoding"))
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	p := NewRemoteWritePusher(RemoteWriteConfig{URL: srv.URL}, prometheus.NewRegistry(), logrus.New())
+
+	if err := p.post(context.Background(), []byte("payload")); err != nil {
+		t.Fatalf("post: %v", err)
+	}
+}
+
+func TestRemoteWritePusherPostNonRetryable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	p := NewRemoteWritePusher(RemoteWriteConfig{URL: srv.URL}, prometheus.NewRegistry(), logrus.New())
+
+	err := p.post(context.Background(), []byte("payload"))
+	if err == nil {
+		t.Fatal("post: want error for 400 response, got nil")
+	}
+
+	var notRetryable *nonRetryableError
+	if !errors.As(err, &notRetryable) {
+		t.Errorf("post error = %v, want *nonRetryableError", err)
+	}
+}
+
+func TestRemoteWritePusherSendAbortsOnNonRetryable(t *testing.T) {
+	attempts := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	p := NewRemoteWritePusher(RemoteWriteConfig{URL: srv.URL}, prometheus.NewRegistry(), logrus.New())
+
+	if err := p.send(context.Background(), []byte("payload")); err == nil {
+		t.Fatal("send: want error, got nil")
+	}
+
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1 (no retry on non-retryable error)", attempts)
+	}
+}
+
+func TestRemoteWritePusherPostHeadersAndAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Scope-OrgID") != "tenant-a" {
+			t.Errorf("X-Scope-OrgID = %q, want tenant-a", r.Header.Get("X-Scope-OrgID"))
+		}
+
+		if auth := r.Header.Get("Authorization"); auth != "Bearer tok123" {
+			t.Errorf("Authorization = %q, want Bearer tok123", auth)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	p := NewRemoteWritePusher(RemoteWriteConfig{
+		URL:         srv.URL,
+		BearerToken: "tok123",
+		Headers:     map[string]string{"X-Scope-OrgID": "tenant-a"},
+	}, prometheus.NewRegistry(), logrus.New())
+
+	if err := p.post(context.Background(), []byte("payload")); err != nil {
+		t.Fatalf("post: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ScrapeSecurity configures TLS and authentication for the scrape endpoint,
+// mirroring the exporter-toolkit patterns used by node_exporter/postgres_exporter.
+type ScrapeSecurity struct {
+	TLSCertFile string
+	TLSKeyFile  string
+	TLSClientCA string
+	AuthUser    string
+	AuthPass    string
+	BearerToken string
+}
+
+// ReadSecretFile reads a file holding a single secret (password or token),
+// trimming surrounding whitespace.
+func ReadSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading secret file %q: %w", path, err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// TLSConfig builds the server tls.Config for s, or nil if no certificate was
+// configured. TLSClientCA, when set, enables mTLS client verification.
+func (s ScrapeSecurity) TLSConfig() (*tls.Config, error) {
+	if s.TLSCertFile == "" && s.TLSKeyFile == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.TLSCertFile, s.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if s.TLSClientCA == "" {
+		return config, nil
+	}
+
+	caCert, err := os.ReadFile(s.TLSClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("reading client CA: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no certificates found in %q", s.TLSClientCA)
+	}
+
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return config, nil
+}
+
+// Protect wraps next with basic auth or bearer token enforcement, whichever
+// is configured. When both are set, only the bearer token is accepted. It
+// returns next unchanged if neither is set.
+func (s ScrapeSecurity) Protect(next http.Handler) http.Handler {
+	if s.BearerToken == "" && s.AuthUser == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="prometheus"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s ScrapeSecurity) authorized(r *http.Request) bool {
+	if s.BearerToken != "" {
+		const prefix = "Bearer "
+
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, prefix) {
+			return false
+		}
+
+		return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, prefix)), []byte(s.BearerToken)) == 1
+	}
+
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(s.AuthUser)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(s.AuthPass)) == 1
+
+	return userOK && passOK
+}
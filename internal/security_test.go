@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScrapeSecurityAuthorizedBasicAuth(t *testing.T) {
+	s := ScrapeSecurity{AuthUser: "admin", AuthPass: "secret"}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.SetBasicAuth("admin", "secret")
+
+	if !s.authorized(req) {
+		t.Error("authorized = false, want true for matching basic auth credentials")
+	}
+
+	req.SetBasicAuth("admin", "wrong")
+
+	if s.authorized(req) {
+		t.Error("authorized = true, want false for wrong password")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if s.authorized(req) {
+		t.Error("authorized = true, want false with no credentials at all")
+	}
+}
+
+func TestScrapeSecurityAuthorizedBearerToken(t *testing.T) {
+	s := ScrapeSecurity{BearerToken: "tok123"}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer tok123")
+
+	if !s.authorized(req) {
+		t.Error("authorized = false, want true for matching bearer token")
+	}
+
+	req.Header.Set("Authorization", "Bearer wrong")
+
+	if s.authorized(req) {
+		t.Error("authorized = true, want false for wrong bearer token")
+	}
+}
+
+func TestScrapeSecurityProtectPassthroughWhenUnconfigured(t *testing.T) {
+	s := ScrapeSecurity{}
+
+	handler := s.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d when no auth is configured", rec.Code, http.StatusOK)
+	}
+}
+
+func TestScrapeSecurityProtectRejectsUnauthorized(t *testing.T) {
+	s := ScrapeSecurity{AuthUser: "admin", AuthPass: "secret"}
+
+	handler := s.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d for missing credentials", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestScrapeSecurityTLSConfigUnset(t *testing.T) {
+	config, err := ScrapeSecurity{}.TLSConfig()
+	if err != nil {
+		t.Fatalf("TLSConfig: %v", err)
+	}
+
+	if config != nil {
+		t.Errorf("TLSConfig = %v, want nil when no certificate is configured", config)
+	}
+}
+
+func TestScrapeSecurityTLSConfigMissingFile(t *testing.T) {
+	s := ScrapeSecurity{TLSCertFile: "testdata/does-not-exist.crt", TLSKeyFile: "testdata/does-not-exist.key"}
+
+	if _, err := s.TLSConfig(); err == nil {
+		t.Fatal("TLSConfig: want error for missing certificate files, got nil")
+	}
+}
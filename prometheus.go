@@ -23,11 +23,15 @@
 package prometheus
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
 	"net/http/pprof"
 	"net/url"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -40,6 +44,15 @@ import (
 
 const defaultPort = 5656
 
+// modeScrape is the default mode, exposing a pull-based scrape endpoint.
+// modeRemoteWrite pushes samples to a remote_write endpoint instead.
+const (
+	modeScrape      = "scrape"
+	modeRemoteWrite = "remote_write"
+)
+
+const defaultPushInterval = 10 * time.Second
+
 // Register the extensions on module initialization.
 func init() {
 	output.RegisterExtension("prometheus", New)
@@ -50,8 +63,62 @@ type options struct {
 	Host      string
 	Subsystem string
 	Namespace string
+
+	Mode               string
+	URL                string
+	PushInterval       time.Duration `schema:"push_interval"`
+	BasicAuthUser      string        `schema:"push_basic_auth_user"`
+	BasicAuthPass      string        `schema:"push_basic_auth_pass"`
+	BearerToken        string        `schema:"push_bearer_token"`
+	InsecureSkipVerify bool          `schema:"insecure_skip_verify"`
+	Headers            map[string]string
+
+	OpenMetrics bool
+
+	// Exemplars lists k6 sample tag keys attached as exemplars to counters
+	// and Trend metrics. Works on Trend regardless of
+	// TrendAsNativeHistogram, since both the classic and native histogram
+	// collectors support ObserveWithExemplar.
+	Exemplars stringList
+
+	Collectors stringList
+
+	TrendAsNativeHistogram bool          `schema:"trend_as_native_histogram"`
+	NativeBucketFactor     float64       `schema:"native_bucket_factor"`
+	NativeMaxBucketNumber  uint32        `schema:"native_max_bucket_number"`
+	NativeMinResetDuration time.Duration `schema:"native_min_reset_duration"`
+
+	Pushgateway    string
+	Job            string
+	PushgwInterval time.Duration `schema:"pushgw_interval"`
+	DeleteOnStop   bool          `schema:"delete_on_stop"`
+	Grouping       map[string]string
+
+	TLSCertFile         string `schema:"tls_cert_file"`
+	TLSKeyFile          string `schema:"tls_key_file"`
+	TLSClientCA         string `schema:"tls_client_ca"`
+	AuthUser            string `schema:"basic_auth_user"`
+	AuthPassFile        string `schema:"basic_auth_pass_file"`
+	AuthBearerTokenFile string `schema:"bearer_token_file"`
+	Pprof               bool
 }
 
+const defaultNativeBucketFactor = 1.1
+
+const defaultPushgatewayJob = "k6"
+
+// groupingPrefix marks query string keys carrying Pushgateway grouping
+// labels, e.g. ?grouping.testid=foo sets the "testid" grouping label.
+const groupingPrefix = "grouping."
+
+// headersPrefix marks query string keys carrying custom remote_write
+// request headers, e.g. ?headers.X-Scope-OrgID=tenant-a.
+const headersPrefix = "headers."
+
+// stringList decodes a comma-separated query string value into a []string,
+// e.g. ?exemplars=trace_id,vu.
+type stringList []string
+
 type Output struct {
 	output.SampleBuffer
 	adapter *internal.PrometheusAdapter
@@ -60,6 +127,17 @@ type Output struct {
 	arg     string
 	logger  logrus.FieldLogger
 	flusher *output.PeriodicFlusher
+
+	pusher     *internal.RemoteWritePusher
+	pushCancel context.CancelFunc
+	pushDone   chan struct{}
+
+	gateway       *internal.PushgatewayClient
+	deleteOnStop  bool
+	gatewayCancel context.CancelFunc
+	gatewayDone   chan struct{}
+
+	httpPool *internal.PushClientPoolCollector
 }
 
 func New(params output.Params) (output.Output, error) {
@@ -83,10 +161,14 @@ func (o *Output) Description() string {
 
 func getopts(qs string) (*options, error) {
 	opts := &options{
-		Port:      defaultPort,
-		Host:      "",
-		Namespace: "",
-		Subsystem: "",
+		Port:               defaultPort,
+		Host:               "",
+		Namespace:          "",
+		Subsystem:          "",
+		Mode:               modeScrape,
+		PushInterval:       defaultPushInterval,
+		NativeBucketFactor: defaultNativeBucketFactor,
+		Job:                defaultPushgatewayJob,
 	}
 
 	if qs == "" {
@@ -98,7 +180,13 @@ func getopts(qs string) (*options, error) {
 		return nil, err
 	}
 
+	opts.Grouping = extractGrouping(v)
+	opts.Headers = extractHeaders(v)
+	stripPrefixed(v, groupingPrefix, headersPrefix)
+
 	decoder := schema.NewDecoder()
+	decoder.RegisterConverter(time.Duration(0), durationConverter)
+	decoder.RegisterConverter(stringList(nil), stringListConverter)
 
 	if err = decoder.Decode(opts, v); err != nil {
 		return nil, err
@@ -107,6 +195,82 @@ func getopts(qs string) (*options, error) {
 	return opts, nil
 }
 
+// stripPrefixed removes every query key starting with any of prefixes from
+// v, in place. Used to drop the dynamically-named keys (grouping.<label>,
+// headers.<name>) that extractGrouping/extractHeaders already consumed,
+// so decoder.Decode can keep rejecting genuine unknown keys instead of
+// needing IgnoreUnknownKeys(true) for the whole options struct.
+func stripPrefixed(v url.Values, prefixes ...string) {
+	for key := range v {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				delete(v, key)
+
+				break
+			}
+		}
+	}
+}
+
+// extractGrouping pulls the Pushgateway grouping labels out of the raw query
+// values, since their key (grouping.<label>) isn't a fixed struct field.
+func extractGrouping(v url.Values) map[string]string {
+	var grouping map[string]string
+
+	for key, values := range v {
+		label, ok := strings.CutPrefix(key, groupingPrefix)
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		if grouping == nil {
+			grouping = make(map[string]string)
+		}
+
+		grouping[label] = values[0]
+	}
+
+	return grouping
+}
+
+// extractHeaders pulls custom remote_write request headers out of the raw
+// query values, since their key (headers.<name>) isn't a fixed struct field.
+func extractHeaders(v url.Values) map[string]string {
+	var headers map[string]string
+
+	for key, values := range v {
+		name, ok := strings.CutPrefix(key, headersPrefix)
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+
+		headers[name] = values[0]
+	}
+
+	return headers
+}
+
+func durationConverter(value string) reflect.Value {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return reflect.Value{}
+	}
+
+	return reflect.ValueOf(d)
+}
+
+func stringListConverter(value string) reflect.Value {
+	if value == "" {
+		return reflect.ValueOf(stringList(nil))
+	}
+
+	return reflect.ValueOf(stringList(strings.Split(value, ",")))
+}
+
 func (o *Output) flush() {
 	t := time.Now()
 	buff := o.SampleBuffer.GetBufferedSamples()
@@ -128,6 +292,50 @@ func (o *Output) Start() error {
 
 	o.adapter.Namespace = opts.Namespace
 	o.adapter.Subsystem = opts.Subsystem
+	o.adapter.ExemplarTags = opts.Exemplars
+	o.adapter.NativeHistogram = internal.NativeHistogramOptions{
+		Enabled:          opts.TrendAsNativeHistogram,
+		BucketFactor:     opts.NativeBucketFactor,
+		MaxBucketNumber:  opts.NativeMaxBucketNumber,
+		MinResetDuration: opts.NativeMinResetDuration,
+	}
+
+	for _, name := range opts.Collectors {
+		switch name {
+		case internal.CollectorK6VUs:
+			o.adapter.K6 = internal.NewK6RuntimeCollector(opts.Namespace, opts.Subsystem)
+		case internal.CollectorPushHTTPPool:
+			o.httpPool = internal.NewPushClientPoolCollector(opts.Namespace, opts.Subsystem)
+		}
+	}
+
+	internal.RegisterCollectors(o.adapter.Registry(), opts.Collectors, o.adapter.K6, o.httpPool)
+
+	switch opts.Mode {
+	case modeRemoteWrite:
+		if err := o.startRemoteWrite(opts); err != nil {
+			return err
+		}
+	default:
+		if err := o.startScrape(opts); err != nil {
+			return err
+		}
+	}
+
+	if opts.Pushgateway != "" {
+		o.startPushgateway(opts)
+	}
+
+	flusher, err := output.NewPeriodicFlusher(time.Second, o.flush)
+	if err != nil {
+		return err
+	}
+	o.flusher = flusher
+
+	return nil
+}
+
+func (o *Output) startScrape(opts *options) error {
 	o.addr = fmt.Sprintf("%s:%d", opts.Host, opts.Port)
 
 	listener, err := net.Listen("tcp", o.addr)
@@ -135,34 +343,195 @@ func (o *Output) Start() error {
 		return err
 	}
 
+	security, err := newScrapeSecurity(opts)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig, err := security.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
 	mux := &http.ServeMux{}
-	mux.Handle("/", o.adapter.Handler())
-	mux.HandleFunc("/debug/pprof/", pprof.Index)
-	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/", o.adapter.Handler(internal.HandlerOptions{OpenMetrics: opts.OpenMetrics}))
+
+	if opts.Pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
 
 	go func() {
-		// if err := http.Serve(listener, o.adapter.Handler()); err != nil {
-		if err := http.Serve(listener, mux); err != nil {
+		if err := http.Serve(listener, security.Protect(mux)); err != nil {
 			o.logger.Error(err)
 		}
 	}()
 
-	flusher, err := output.NewPeriodicFlusher(time.Second, o.flush)
-	if err != nil {
-		return err
+	return nil
+}
+
+// newScrapeSecurity resolves opts into an internal.ScrapeSecurity, reading
+// the password/token files up front so Start fails fast on a bad path.
+func newScrapeSecurity(opts *options) (internal.ScrapeSecurity, error) {
+	security := internal.ScrapeSecurity{
+		TLSCertFile: opts.TLSCertFile,
+		TLSKeyFile:  opts.TLSKeyFile,
+		TLSClientCA: opts.TLSClientCA,
+		AuthUser:    opts.AuthUser,
+	}
+
+	if opts.AuthPassFile != "" {
+		pass, err := internal.ReadSecretFile(opts.AuthPassFile)
+		if err != nil {
+			return internal.ScrapeSecurity{}, err
+		}
+
+		security.AuthPass = pass
+	}
+
+	if opts.AuthBearerTokenFile != "" {
+		token, err := internal.ReadSecretFile(opts.AuthBearerTokenFile)
+		if err != nil {
+			return internal.ScrapeSecurity{}, err
+		}
+
+		security.BearerToken = token
+	}
+
+	return security, nil
+}
+
+func (o *Output) startRemoteWrite(opts *options) error {
+	if opts.URL == "" {
+		return fmt.Errorf("remote_write mode requires a url argument")
+	}
+
+	if opts.PushInterval <= 0 {
+		return fmt.Errorf("push_interval must be positive, got %s", opts.PushInterval)
 	}
-	o.flusher = flusher
+
+	o.addr = opts.URL
+
+	o.pusher = internal.NewRemoteWritePusher(internal.RemoteWriteConfig{
+		URL:                opts.URL,
+		PushInterval:       opts.PushInterval,
+		BasicAuthUser:      opts.BasicAuthUser,
+		BasicAuthPass:      opts.BasicAuthPass,
+		BearerToken:        opts.BearerToken,
+		Headers:            opts.Headers,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+		HTTPPool:           o.httpPool,
+	}, o.adapter.Registry(), o.logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	o.pushCancel = cancel
+	o.pushDone = make(chan struct{})
+
+	go o.runPush(ctx, opts.PushInterval)
 
 	return nil
 }
 
+func (o *Output) runPush(ctx context.Context, interval time.Duration) {
+	defer close(o.pushDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := o.pusher.Push(ctx); err != nil {
+				o.logger.WithError(err).Error("remote_write push failed")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// startPushgateway wires a Pushgateway fallback: a final push (and optional
+// delete) happens on Stop, plus periodic pushes in between when
+// opts.PushgwInterval is set.
+func (o *Output) startPushgateway(opts *options) {
+	o.gateway = internal.NewPushgatewayClient(internal.PushgatewayConfig{
+		URL:      opts.Pushgateway,
+		Job:      opts.Job,
+		Grouping: opts.Grouping,
+		HTTPPool: o.httpPool,
+	}, o.adapter.Registry())
+	o.deleteOnStop = opts.DeleteOnStop
+
+	if opts.PushgwInterval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	o.gatewayCancel = cancel
+	o.gatewayDone = make(chan struct{})
+
+	go o.runGatewayPush(ctx, opts.PushgwInterval)
+}
+
+func (o *Output) runGatewayPush(ctx context.Context, interval time.Duration) {
+	defer close(o.gatewayDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := o.gateway.Push(); err != nil {
+				o.logger.WithError(err).Error("pushgateway push failed")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func (o *Output) Stop() error {
 	if o.flusher != nil {
 		o.flusher.Stop()
 		o.flusher = nil
 	}
+
+	if o.pushCancel != nil {
+		if err := o.pusher.Push(context.Background()); err != nil {
+			o.logger.WithError(err).Error("final remote_write push failed")
+		}
+
+		o.pushCancel()
+		<-o.pushDone
+		o.pushCancel = nil
+	}
+
+	if o.gatewayCancel != nil {
+		o.gatewayCancel()
+		<-o.gatewayDone
+		o.gatewayCancel = nil
+	}
+
+	if o.gateway != nil {
+		pushErr := o.gateway.Push()
+		if pushErr != nil {
+			o.logger.WithError(pushErr).Error("final pushgateway push failed")
+		}
+
+		if o.deleteOnStop && pushErr == nil {
+			if err := o.gateway.Delete(); err != nil {
+				o.logger.WithError(err).Error("pushgateway delete failed")
+			}
+		}
+	}
+
 	return nil
 }
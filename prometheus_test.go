@@ -0,0 +1,140 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetoptsDefaults(t *testing.T) {
+	opts, err := getopts("")
+	if err != nil {
+		t.Fatalf("getopts: %v", err)
+	}
+
+	if opts.Port != defaultPort {
+		t.Errorf("Port = %d, want %d", opts.Port, defaultPort)
+	}
+
+	if opts.Mode != modeScrape {
+		t.Errorf("Mode = %q, want %q", opts.Mode, modeScrape)
+	}
+}
+
+func TestGetoptsRemoteWrite(t *testing.T) {
+	qs := "mode=remote_write&url=https://example.test/api/v1/write&push_interval=5s" +
+		"&push_basic_auth_user=alice&push_basic_auth_pass=secret&insecure_skip_verify=true" +
+		"&headers.X-Scope-OrgID=tenant-a"
+
+	opts, err := getopts(qs)
+	if err != nil {
+		t.Fatalf("getopts: %v", err)
+	}
+
+	if opts.Mode != modeRemoteWrite {
+		t.Errorf("Mode = %q, want %q", opts.Mode, modeRemoteWrite)
+	}
+
+	if opts.URL != "https://example.test/api/v1/write" {
+		t.Errorf("URL = %q", opts.URL)
+	}
+
+	if opts.PushInterval != 5*time.Second {
+		t.Errorf("PushInterval = %s, want 5s", opts.PushInterval)
+	}
+
+	if opts.BasicAuthUser != "alice" || opts.BasicAuthPass != "secret" {
+		t.Errorf("BasicAuthUser/Pass = %q/%q", opts.BasicAuthUser, opts.BasicAuthPass)
+	}
+
+	if !opts.InsecureSkipVerify {
+		t.Error("InsecureSkipVerify = false, want true")
+	}
+
+	if opts.Headers["X-Scope-OrgID"] != "tenant-a" {
+		t.Errorf("Headers[X-Scope-OrgID] = %q, want %q", opts.Headers["X-Scope-OrgID"], "tenant-a")
+	}
+}
+
+func TestGetoptsNativeHistogram(t *testing.T) {
+	qs := "trend_as_native_histogram=true&native_bucket_factor=1.1" +
+		"&native_max_bucket_number=100&native_min_reset_duration=1h"
+
+	opts, err := getopts(qs)
+	if err != nil {
+		t.Fatalf("getopts: %v", err)
+	}
+
+	if !opts.TrendAsNativeHistogram {
+		t.Error("TrendAsNativeHistogram = false, want true")
+	}
+
+	if opts.NativeBucketFactor != 1.1 {
+		t.Errorf("NativeBucketFactor = %v, want 1.1", opts.NativeBucketFactor)
+	}
+
+	if opts.NativeMaxBucketNumber != 100 {
+		t.Errorf("NativeMaxBucketNumber = %d, want 100", opts.NativeMaxBucketNumber)
+	}
+
+	if opts.NativeMinResetDuration != time.Hour {
+		t.Errorf("NativeMinResetDuration = %s, want 1h", opts.NativeMinResetDuration)
+	}
+}
+
+func TestGetoptsPushgateway(t *testing.T) {
+	qs := "pushgateway=https://pushgw.example.test&job=my-job&pushgw_interval=30s" +
+		"&delete_on_stop=true&grouping.instance=a"
+
+	opts, err := getopts(qs)
+	if err != nil {
+		t.Fatalf("getopts: %v", err)
+	}
+
+	if opts.Pushgateway != "https://pushgw.example.test" {
+		t.Errorf("Pushgateway = %q", opts.Pushgateway)
+	}
+
+	if opts.PushgwInterval != 30*time.Second {
+		t.Errorf("PushgwInterval = %s, want 30s", opts.PushgwInterval)
+	}
+
+	if !opts.DeleteOnStop {
+		t.Error("DeleteOnStop = false, want true")
+	}
+
+	if opts.Grouping["instance"] != "a" {
+		t.Errorf("Grouping[instance] = %q, want %q", opts.Grouping["instance"], "a")
+	}
+}
+
+func TestGetoptsRejectsUnknownKey(t *testing.T) {
+	if _, err := getopts("pushgatewai=https://typo.example.test"); err == nil {
+		t.Fatal("getopts: want error for unknown key, got nil")
+	}
+}
+
+func TestGetoptsScrapeSecurity(t *testing.T) {
+	qs := "tls_cert_file=server.crt&tls_key_file=server.key&tls_client_ca=ca.crt" +
+		"&basic_auth_user=admin&basic_auth_pass_file=pass.txt&bearer_token_file=token.txt&pprof=true"
+
+	opts, err := getopts(qs)
+	if err != nil {
+		t.Fatalf("getopts: %v", err)
+	}
+
+	if opts.TLSCertFile != "server.crt" || opts.TLSKeyFile != "server.key" || opts.TLSClientCA != "ca.crt" {
+		t.Errorf("TLSCertFile/TLSKeyFile/TLSClientCA = %q/%q/%q", opts.TLSCertFile, opts.TLSKeyFile, opts.TLSClientCA)
+	}
+
+	if opts.AuthUser != "admin" {
+		t.Errorf("AuthUser = %q, want %q", opts.AuthUser, "admin")
+	}
+
+	if opts.AuthPassFile != "pass.txt" || opts.AuthBearerTokenFile != "token.txt" {
+		t.Errorf("AuthPassFile/AuthBearerTokenFile = %q/%q", opts.AuthPassFile, opts.AuthBearerTokenFile)
+	}
+
+	if !opts.Pprof {
+		t.Error("Pprof = false, want true")
+	}
+}